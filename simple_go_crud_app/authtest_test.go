@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+// TestMain wires up the package-level store/eventLog globals the handlers
+// depend on, since these tests exercise them directly rather than through
+// main().
+func TestMain(m *testing.M) {
+	store = NewMemoryStore()
+	userStore = store.(UserStore)
+
+	eventLogFile, err := os.CreateTemp("", "authtest-events-*.jsonl")
+	if err != nil {
+		fmt.Println("[-] Error creating temp event log:", err)
+		os.Exit(1)
+	}
+	eventLogFile.Close()
+	defer os.Remove(eventLogFile.Name())
+
+	eventLog, err = NewEventLog(eventLogFile.Name(), store)
+	if err != nil {
+		fmt.Println("[-] Error initializing event log:", err)
+		os.Exit(1)
+	}
+
+	os.Exit(m.Run())
+}
+
+func registerAndLogin(username, password string) (string, error) {
+	registerBody, _ := json.Marshal(RegisterRequest{Username: username, Password: password})
+	registerReq := httptest.NewRequest(http.MethodPost, "/register", bytes.NewReader(registerBody))
+	registerReq.Header.Set("Content-Type", "application/json")
+	registerReq.Header.Set("Accept", "application/json")
+	registerRec := httptest.NewRecorder()
+	handleRegister(registerRec, registerReq)
+	if registerRec.Code != http.StatusCreated {
+		return "", fmt.Errorf("register: unexpected status %d", registerRec.Code)
+	}
+
+	loginBody, _ := json.Marshal(LoginRequest{Username: username, Password: password})
+	loginReq := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(loginBody))
+	loginReq.Header.Set("Content-Type", "application/json")
+	loginReq.Header.Set("Accept", "application/json")
+	loginRec := httptest.NewRecorder()
+	handleLogin(loginRec, loginReq)
+	if loginRec.Code != http.StatusOK {
+		return "", fmt.Errorf("login: unexpected status %d", loginRec.Code)
+	}
+
+	var loginResp LoginResponse
+	if err := json.Unmarshal(loginRec.Body.Bytes(), &loginResp); err != nil {
+		return "", err
+	}
+	return loginResp.Token, nil
+}
+
+func TestTokenIssuance(t *testing.T) {
+	token, err := registerAndLogin("authtest-"+uuid.New().String(), "hunter2")
+	if err != nil {
+		t.Fatalf("register/login: %v", err)
+	}
+	if _, err := parseToken(token); err != nil {
+		t.Fatalf("issued token did not parse: %v", err)
+	}
+}
+
+func TestForbiddenCrossUserAccess(t *testing.T) {
+	tokenA, err := registerAndLogin("authtest-a-"+uuid.New().String(), "hunter2")
+	if err != nil {
+		t.Fatalf("register/login user A: %v", err)
+	}
+	tokenB, err := registerAndLogin("authtest-b-"+uuid.New().String(), "hunter2")
+	if err != nil {
+		t.Fatalf("register/login user B: %v", err)
+	}
+
+	createBody, _ := json.Marshal(UserTodoRequest{Todo: "owned by A"})
+	createReq := httptest.NewRequest(http.MethodPost, "/todos", bytes.NewReader(createBody))
+	createReq.Header.Set("Content-Type", "application/json")
+	createReq.Header.Set("Authorization", "Bearer "+tokenA)
+	createRec := httptest.NewRecorder()
+	authMiddleware(createTodo)(createRec, createReq)
+	if createRec.Code != http.StatusAccepted {
+		t.Fatalf("create: unexpected status %d", createRec.Code)
+	}
+
+	todos, err := store.List(context.Background())
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	var createdID string
+	for _, todo := range todos {
+		if todo.Todo == "owned by A" {
+			createdID = todo.Id
+		}
+	}
+	if createdID == "" {
+		t.Fatal("could not find created todo")
+	}
+
+	getReq := httptest.NewRequest(http.MethodGet, "/todos/"+createdID, nil)
+	getReq.SetPathValue("id", createdID)
+	getReq.Header.Set("Authorization", "Bearer "+tokenB)
+	getRec := httptest.NewRecorder()
+	authMiddleware(getTodoById)(getRec, getReq)
+	if getRec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for cross-user access, got %d", getRec.Code)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// EventType identifies the kind of mutation that happened to a todo.
+type EventType string
+
+const (
+	EventTodoCreated EventType = "TodoCreated"
+	EventTodoUpdated EventType = "TodoUpdated"
+	EventTodoDeleted EventType = "TodoDeleted"
+)
+
+// Event is one entry in the append-only event log. Todo is always
+// populated (for TodoDeleted it carries the deleted todo's last state,
+// chiefly so subscribers can filter by OwnerID); Id is populated for
+// TodoDeleted.
+type Event struct {
+	Seq  int64     `json:"seq"`
+	Type EventType `json:"type"`
+	Todo Todo      `json:"todo,omitempty"`
+	Id   string    `json:"id,omitempty"`
+}
+
+// EventLog is the source of truth for todo mutations. Every Create/Update/
+// Delete is appended here first; the TodoStore handed to NewEventLog is
+// just the read-side projection, rebuilt by folding the log from the
+// start on boot and kept current as events are emitted. Subscribers (the
+// SSE stream) get each event fanned out as it's appended.
+type EventLog struct {
+	mu    sync.Mutex
+	path  string
+	file  *os.File
+	seq   int64
+	store TodoStore
+
+	subMu sync.Mutex
+	subs  map[chan Event]struct{}
+}
+
+func NewEventLog(path string, store TodoStore) (*EventLog, error) {
+	l := &EventLog{path: path, store: store, subs: make(map[chan Event]struct{})}
+
+	if err := l.replayIntoStore(); err != nil {
+		return nil, fmt.Errorf("eventlog: replay %s: %w", path, err)
+	}
+
+	// The replay above just re-applied every historical event through the
+	// store's own Create/Update/Delete, which for a durable store like
+	// JSONStreamStore appends a line per event. Compact back down to one
+	// line per live row so a restart doesn't leave that log growing by the
+	// full event history every time.
+	if c, ok := store.(Compactable); ok {
+		if err := c.Compact(); err != nil {
+			return nil, fmt.Errorf("eventlog: compact projection after replay: %w", err)
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("eventlog: open %s: %w", path, err)
+	}
+	l.file = file
+	return l, nil
+}
+
+// replayIntoStore folds every event on disk into the projection store and
+// advances seq to the highest sequence number seen.
+func (l *EventLog) replayIntoStore() error {
+	events, err := l.Replay(0)
+	if err != nil {
+		return err
+	}
+	for _, evt := range events {
+		if err := l.apply(context.Background(), evt); err != nil {
+			return err
+		}
+		if evt.Seq > l.seq {
+			l.seq = evt.Seq
+		}
+	}
+	return nil
+}
+
+func (l *EventLog) apply(ctx context.Context, evt Event) error {
+	switch evt.Type {
+	case EventTodoCreated:
+		return l.store.Create(ctx, evt.Todo)
+	case EventTodoUpdated:
+		return l.store.Update(ctx, evt.Todo)
+	case EventTodoDeleted:
+		return l.store.Delete(ctx, evt.Id)
+	default:
+		return fmt.Errorf("eventlog: unknown event type %q", evt.Type)
+	}
+}
+
+// Emit folds the event into the projection store, appends it to the log,
+// and fans it out to subscribers. The projection update and the append
+// happen under the same lock so readers never see a todo the log doesn't
+// yet have a record of.
+func (l *EventLog) Emit(ctx context.Context, evtType EventType, todo Todo, id string) (Event, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	evt := Event{Seq: l.seq + 1, Type: evtType, Todo: todo, Id: id}
+
+	if err := l.apply(ctx, evt); err != nil {
+		return Event{}, err
+	}
+	if err := json.NewEncoder(l.file).Encode(evt); err != nil {
+		return Event{}, err
+	}
+
+	l.seq = evt.Seq
+	l.publish(evt)
+	return evt, nil
+}
+
+// Replay returns every event with a sequence number greater than since,
+// read directly from the on-disk log.
+func (l *EventLog) Replay(since int64) ([]Event, error) {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_RDONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var events []Event
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var evt Event
+		if err := json.Unmarshal(line, &evt); err != nil {
+			return nil, fmt.Errorf("corrupt event: %w", err)
+		}
+		if evt.Seq > since {
+			events = append(events, evt)
+		}
+	}
+	return events, scanner.Err()
+}
+
+// Subscribe registers a channel that receives every event emitted from
+// this point on. Callers must invoke cancel when done to avoid leaking
+// the channel.
+func (l *EventLog) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 16)
+
+	l.subMu.Lock()
+	l.subs[ch] = struct{}{}
+	l.subMu.Unlock()
+
+	cancel := func() {
+		l.subMu.Lock()
+		defer l.subMu.Unlock()
+		if _, ok := l.subs[ch]; ok {
+			delete(l.subs, ch)
+			close(ch)
+		}
+	}
+	return ch, cancel
+}
+
+func (l *EventLog) publish(evt Event) {
+	l.subMu.Lock()
+	defer l.subMu.Unlock()
+
+	for ch := range l.subs {
+		select {
+		case ch <- evt:
+		default:
+			// Slow subscriber; drop the event rather than block the writer.
+		}
+	}
+}
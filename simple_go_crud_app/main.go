@@ -4,10 +4,10 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"os"
-	"sync"
+	"strconv"
+	"strings"
 	"text/template"
 
 	"github.com/google/uuid"
@@ -22,8 +22,10 @@ var (
 )
 
 type Todo struct {
-	Id   string `json:"id"`
-	Todo string `json:"todo"`
+	Id        string `json:"id"`
+	Todo      string `json:"todo"`
+	OwnerID   string `json:"ownerId"`
+	Completed bool   `json:"completed"`
 }
 
 type UserTodoRequest struct {
@@ -35,6 +37,53 @@ func healthCheck(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// wantsJSON decides whether a handler should respond with JSON or with an
+// HTML fragment, so the same handler can serve both the JSON API and the
+// HTMX-driven UI. It compares each comma-separated media range against
+// "application/json" by exact type, ignoring parameters like ";q=0.9",
+// rather than a substring check, so a header like "application/jsonlines"
+// isn't mistaken for wanting JSON.
+func wantsJSON(r *http.Request) bool {
+	for _, mediaRange := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(mediaRange, ";", 2)[0])
+		if mediaType == "application/json" {
+			return true
+		}
+	}
+	return false
+}
+
+// handleIndex renders the full todo list page for the caller's todos.
+func handleIndex(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("[-] Render todo index")
+
+	ownerID := userIDFromContext(r.Context())
+
+	allTodos, err := store.List(r.Context())
+	if err != nil {
+		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	todos := make([]Todo, 0, len(allTodos))
+	for _, todo := range allTodos {
+		if todo.OwnerID == ownerID {
+			todos = append(todos, todo)
+		}
+	}
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(todos)
+		return
+	}
+
+	if err := templates.ExecuteTemplate(w, "index.html", todos); err != nil {
+		fmt.Println("[-] Error rendering index:", err)
+		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
+	}
+}
+
 func createTodo(w http.ResponseWriter, r *http.Request) {
 	// Since the UUID generation could panic I need to recover from that
 	defer func() {
@@ -45,9 +94,14 @@ func createTodo(w http.ResponseWriter, r *http.Request) {
 	fmt.Println("[-] Create Todo")
 
 	var userTodoRequest UserTodoRequest
-	if err := json.NewDecoder(r.Body).Decode(&userTodoRequest); err != nil {
-		http.Error(w, ErrJsonInvalid.Error(), http.StatusBadRequest)
-		return
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&userTodoRequest); err != nil {
+			http.Error(w, ErrJsonInvalid.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		// HTMX submits the todo form as regular form data.
+		userTodoRequest.Todo = r.FormValue("todo")
 	}
 
 	if userTodoRequest.Todo == "" {
@@ -55,37 +109,57 @@ func createTodo(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	id := uuid.New().String()
+	createdTodo := Todo{
+		Id:      uuid.New().String(),
+		Todo:    userTodoRequest.Todo,
+		OwnerID: userIDFromContext(r.Context()),
+	}
 
-	todosDb.mu.Lock()
-	defer todosDb.mu.Unlock()
+	if _, err := eventLog.Emit(r.Context(), EventTodoCreated, createdTodo, ""); err != nil {
+		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	createdTodo := Todo{
-		Id:   id,
-		Todo: userTodoRequest.Todo,
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(createdTodo)
+		return
 	}
-	todosDb.todos[id] = createdTodo
+
 	w.WriteHeader(http.StatusAccepted)
+	templates.ExecuteTemplate(w, "todo-item.html", createdTodo)
 }
 
 func getTodos(w http.ResponseWriter, r *http.Request) {
-	defer todosDb.mu.RUnlock()
 	fmt.Println("[-] Get All Todos")
 
-	todosDb.mu.RLock()
+	ownerID := userIDFromContext(r.Context())
 
-	if len(todosDb.todos) == 0 {
+	allTodos, err := store.List(r.Context())
+	if err != nil {
 		http.Error(
 			w,
-			ErrTodoEmpty.Error(),
-			http.StatusNotFound,
+			ErrInternalServerError.Error(),
+			http.StatusInternalServerError,
 		)
 		return
 	}
-	todos := make([]Todo, 0, len(todosDb.todos))
 
-	for _, todo := range todosDb.todos {
-		todos = append(todos, todo)
+	todos := make([]Todo, 0, len(allTodos))
+	for _, todo := range allTodos {
+		if todo.OwnerID == ownerID {
+			todos = append(todos, todo)
+		}
+	}
+
+	if len(todos) == 0 {
+		http.Error(
+			w,
+			ErrTodoEmpty.Error(),
+			http.StatusNotFound,
+		)
+		return
 	}
 
 	jsonTodos, err := json.Marshal(todos)
@@ -108,11 +182,8 @@ func getTodoById(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	fmt.Println("[-] Get todo by id %v", id)
 
-	todosDb.mu.RLock()
-	todo, ok := todosDb.todos[id]
-	todosDb.mu.RUnlock()
-
-	if !ok {
+	todo, err := store.Get(r.Context(), id)
+	if err != nil || todo.OwnerID != userIDFromContext(r.Context()) {
 		http.Error(
 			w,
 			ErrTodoNotFound.Error(),
@@ -140,16 +211,11 @@ func patchTodoById(w http.ResponseWriter, r *http.Request) {
 	id := r.PathValue("id")
 	fmt.Println("[-] Patch todo by id %v", id)
 
-	todosDb.mu.Lock()
+	ownerID := userIDFromContext(r.Context())
 
-	defer todosDb.mu.Unlock()
-
-	if _, ok := todosDb.todos[id]; !ok {
-		http.Error(
-			w,
-			ErrTodoNotFound.Error(),
-			http.StatusBadRequest,
-		)
+	existing, err := store.Get(r.Context(), id)
+	if err != nil || existing.OwnerID != ownerID {
+		http.Error(w, ErrTodoNotFound.Error(), http.StatusNotFound)
 		return
 	}
 
@@ -164,11 +230,21 @@ func patchTodoById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	updatedTodo := Todo{
-		Id:   id,
-		Todo: userTodoRequest.Todo,
+		Id:        id,
+		Todo:      userTodoRequest.Todo,
+		OwnerID:   ownerID,
+		Completed: existing.Completed,
+	}
+
+	if _, err := eventLog.Emit(r.Context(), EventTodoUpdated, updatedTodo, ""); err != nil {
+		http.Error(
+			w,
+			ErrTodoNotFound.Error(),
+			http.StatusBadRequest,
+		)
+		return
 	}
 
-	todosDb.todos[id] = updatedTodo
 	jsonUpdatedTodo, err := json.Marshal(updatedTodo)
 
 	if err != nil {
@@ -190,10 +266,13 @@ func deleteTodoById(w http.ResponseWriter, r *http.Request) {
 
 	fmt.Println("[-] Delete todo by id %v", id)
 
-	todosDb.mu.Lock()
-	defer todosDb.mu.Unlock()
+	existing, err := store.Get(r.Context(), id)
+	if err != nil || existing.OwnerID != userIDFromContext(r.Context()) {
+		http.Error(w, ErrTodoNotFound.Error(), http.StatusNotFound)
+		return
+	}
 
-	if _, ok := todosDb.todos[id]; !ok {
+	if _, err := eventLog.Emit(r.Context(), EventTodoDeleted, existing, id); err != nil {
 		http.Error(
 			w,
 			ErrTodoNotFound.Error(),
@@ -202,103 +281,182 @@ func deleteTodoById(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	delete(todosDb.todos, id)
+	// htmx only swaps on a successful response body, and StatusNoContent
+	// (204) skips the swap entirely, so `hx-delete`/`hx-swap="outerHTML"`
+	// would never remove the row. Respond 200 with an empty body instead.
+	w.WriteHeader(http.StatusOK)
+}
 
-	w.WriteHeader(http.StatusNoContent)
+// handleToggleTodo flips a todo's Completed flag, used by the HTMX "Done"
+// / "Undo" button.
+func handleToggleTodo(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	fmt.Println("[-] Toggle todo by id", id)
 
-}
+	existing, err := store.Get(r.Context(), id)
+	if err != nil || existing.OwnerID != userIDFromContext(r.Context()) {
+		http.Error(w, ErrTodoNotFound.Error(), http.StatusNotFound)
+		return
+	}
 
-// Compile templates on start of the application
-var templates = template.Must(template.ParseFiles("public/upload.html"))
+	updatedTodo := existing
+	updatedTodo.Completed = !existing.Completed
 
-const fileUploadFolder = "files"
+	if _, err := eventLog.Emit(r.Context(), EventTodoUpdated, updatedTodo, ""); err != nil {
+		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
+		return
+	}
 
-func createFileUploadDir(fileUploadFolder string) {
-	err := os.MkdirAll(fileUploadFolder, 0755)
-	if err != nil {
-		fmt.Println("[-] Error creating directory:", err)
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(updatedTodo)
+		return
 	}
-}
 
-func handleGetFile(w http.ResponseWriter, r *http.Request) {
-	templates.ExecuteTemplate(w, "upload.html", nil)
+	templates.ExecuteTemplate(w, "todo-item.html", updatedTodo)
 }
 
-func handleFileUpload(w http.ResponseWriter, r *http.Request) {
-	const TENMB = 10 << 20
+// handleTodoEvents streams todo events as Server-Sent Events, scoped to
+// the caller's own todos. The `since` query parameter replays history
+// from that sequence number before switching to live updates, so a
+// client can resume after a disconnect without missing anything.
+func handleTodoEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	ownerID := userIDFromContext(r.Context())
 
-	// Max upload size limit 10 mb files
-	r.ParseMultipartForm(TENMB)
+	var since int64
+	if s := r.URL.Query().Get("since"); s != "" {
+		parsed, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			http.Error(w, ErrJsonInvalid.Error(), http.StatusBadRequest)
+			return
+		}
+		since = parsed
+	}
 
-	file, header, err := r.FormFile("myFile")
+	backlog, err := eventLog.Replay(since)
 	if err != nil {
-		http.Error(
-			w,
-			ErrInternalServerError.Error(),
-			http.StatusInternalServerError,
-		)
+		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	defer file.Close()
-	fmt.Println("[-] Uploaded File Name", header.Filename)
-	fmt.Println("[-] Uploaded File Size", header.Size)
-	fmt.Println("[-] Uploaded File Mime Header", header.Header)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
 
-	// create the file
-	dst, err := os.Create(fmt.Sprintf("%v/%v", fileUploadFolder, header.Filename))
-	defer dst.Close()
+	for _, evt := range backlog {
+		if evt.Todo.OwnerID == ownerID {
+			writeSSEEvent(w, evt)
+		}
+	}
+	flusher.Flush()
+
+	ch, cancel := eventLog.Subscribe()
+	defer cancel()
+
+	for {
+		select {
+		case evt, ok := <-ch:
+			if !ok {
+				return
+			}
+			if evt.Todo.OwnerID == ownerID {
+				writeSSEEvent(w, evt)
+				flusher.Flush()
+			}
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
 
+func writeSSEEvent(w http.ResponseWriter, evt Event) {
+	data, err := json.Marshal(evt)
 	if err != nil {
-		fmt.Println("[-] Error in File Creation %v", err.Error())
-		http.Error(
-			w,
-			ErrInternalServerError.Error(),
-			http.StatusInternalServerError,
-		)
+		fmt.Println("[-] Error marshaling event:", err)
 		return
 	}
+	fmt.Fprintf(w, "id: %d\ndata: %s\n\n", evt.Seq, data)
+}
 
-	if _, err := io.Copy(dst, file); err != nil {
-		fmt.Println("[-] Error in Copying File form src to dst %v", err.Error())
-		http.Error(
-			w,
-			ErrInternalServerError.Error(),
-			http.StatusInternalServerError,
-		)
-		return
+// Compile templates on start of the application
+var templates = template.Must(template.ParseGlob("public/*.html"))
+
+const fileUploadFolder = "files"
+
+func createFileUploadDir(fileUploadFolder string) {
+	err := os.MkdirAll(fileUploadFolder, 0755)
+	if err != nil {
+		fmt.Println("[-] Error creating directory:", err)
 	}
+}
 
-	fmt.Println("File uploaded successfully")
-	w.WriteHeader(http.StatusNoContent)
+// handleLoginPage and handleRegisterPage serve the unauthenticated forms
+// browsers need to obtain the session cookie authMiddleware requires,
+// since / redirects here instead of just returning a bare 401.
+func handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	templates.ExecuteTemplate(w, "login.html", nil)
 }
 
-type TodosDB struct {
-	mu    sync.RWMutex
-	todos map[string]Todo
+func handleRegisterPage(w http.ResponseWriter, r *http.Request) {
+	templates.ExecuteTemplate(w, "register.html", nil)
 }
 
-var todosDb TodosDB
+var store TodoStore
+var eventLog *EventLog
 
 func main() {
-	todosDb = TodosDB{
-		todos: make(map[string]Todo),
+	var err error
+	store, err = newStoreFromEnv()
+	if err != nil {
+		fmt.Println("[-] Error initializing todo store:", err)
+		os.Exit(1)
 	}
+
+	eventLogPath := os.Getenv("TODO_EVENT_LOG_PATH")
+	if eventLogPath == "" {
+		eventLogPath = "todos.events.jsonl"
+	}
+	eventLog, err = NewEventLog(eventLogPath, store)
+	if err != nil {
+		fmt.Println("[-] Error initializing event log:", err)
+		os.Exit(1)
+	}
+
+	// Every TodoStore driver also implements UserStore.
+	userStore = store.(UserStore)
+
+	uploadsDb = UploadsDB{uploads: make(map[string]*Upload)}
+
 	mux := http.NewServeMux()
 
 	createFileUploadDir(fileUploadFolder)
+	createUploadTmpDir()
 
 	// Add handlers
 	mux.HandleFunc("GET /health-check", healthCheck)
-	mux.HandleFunc("GET /todos", getTodos)
-	mux.HandleFunc("POST /todos", createTodo)
-	mux.HandleFunc("GET /todos/{id}", getTodoById)
-	mux.HandleFunc("PATCH /todos/{id}", patchTodoById)
-	mux.HandleFunc("DELETE /todos/{id}", deleteTodoById)
+	mux.HandleFunc("GET /register", handleRegisterPage)
+	mux.HandleFunc("POST /register", handleRegister)
+	mux.HandleFunc("GET /login", handleLoginPage)
+	mux.HandleFunc("POST /login", handleLogin)
+	mux.HandleFunc("GET /", authMiddleware(handleIndex))
+	mux.HandleFunc("GET /todos", authMiddleware(getTodos))
+	mux.HandleFunc("POST /todos", authMiddleware(createTodo))
+	mux.HandleFunc("GET /todos/{id}", authMiddleware(getTodoById))
+	mux.HandleFunc("PATCH /todos/{id}", authMiddleware(patchTodoById))
+	mux.HandleFunc("PATCH /todos/{id}/toggle", authMiddleware(handleToggleTodo))
+	mux.HandleFunc("DELETE /todos/{id}", authMiddleware(deleteTodoById))
+	mux.HandleFunc("GET /todos/events", authMiddleware(handleTodoEvents))
 
 	// File Uplaod
-	mux.HandleFunc("GET /file", handleGetFile)
-	mux.HandleFunc("POST /file", handleFileUpload)
+	mux.HandleFunc("POST /file/uploads/", handleUploadStart)
+	mux.HandleFunc("PATCH /file/uploads/{id}", handleUploadAppend)
+	mux.HandleFunc("PUT /file/uploads/{id}", handleUploadFinalize)
 
 	// Start the server
 	fmt.Println("Started server at :8080 ...")
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+)
+
+// TodoStore is the persistence contract every todo driver implements.
+// Handlers only ever talk to this interface so the backing storage can be
+// swapped without touching request handling.
+type TodoStore interface {
+	Create(ctx context.Context, todo Todo) error
+	Get(ctx context.Context, id string) (Todo, error)
+	List(ctx context.Context) ([]Todo, error)
+	Update(ctx context.Context, todo Todo) error
+	Delete(ctx context.Context, id string) error
+}
+
+// UserStore is the persistence contract for user accounts. Every
+// TodoStore driver also implements this, so picking a TODO_STORE driver
+// picks where both todos and users live.
+type UserStore interface {
+	CreateUser(ctx context.Context, user User) error
+	GetUserByUsername(ctx context.Context, username string) (User, error)
+}
+
+// Compactable is implemented by stores whose durable log grows with every
+// write. NewEventLog compacts the projection through this once its full
+// replay from the event log is done, since that replay writes through
+// the store's own append path for every historical event.
+type Compactable interface {
+	Compact() error
+}
+
+// newStoreFromEnv picks a TodoStore implementation based on TODO_STORE:
+//
+//	memory     - process-local map, the default, loses data on restart
+//	jsonstream - append-only JSON stream file with an in-memory index
+//	redis      - Redis-backed store, address from TODO_STORE_REDIS_ADDR
+func newStoreFromEnv() (TodoStore, error) {
+	switch driver := os.Getenv("TODO_STORE"); driver {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "jsonstream":
+		path := os.Getenv("TODO_STORE_PATH")
+		if path == "" {
+			path = "todos.jsonl"
+		}
+		return NewJSONStreamStore(path)
+	case "redis":
+		addr := os.Getenv("TODO_STORE_REDIS_ADDR")
+		if addr == "" {
+			addr = "localhost:6379"
+		}
+		return NewRedisStore(addr), nil
+	default:
+		return nil, fmt.Errorf("unknown TODO_STORE driver %q", driver)
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+)
+
+// jsonStreamOp is one line of the append-only stream file.
+type jsonStreamOp struct {
+	Op   string `json:"op"` // "put", "delete", "user_put"
+	Todo Todo   `json:"todo,omitempty"`
+	Id   string `json:"id,omitempty"`
+	User User   `json:"user,omitempty"`
+}
+
+// JSONStreamStore persists todos as an append-only stream of operations,
+// one JSON object per line, and keeps an in-memory index for O(1) reads.
+// The file is compacted to a single "put" per surviving todo on every
+// boot, so restarts don't leave the log growing forever.
+type JSONStreamStore struct {
+	mu    sync.RWMutex
+	path  string
+	index map[string]Todo
+	users map[string]User // keyed by username
+	file  *os.File
+}
+
+func NewJSONStreamStore(path string) (*JSONStreamStore, error) {
+	s := &JSONStreamStore{
+		path:  path,
+		index: make(map[string]Todo),
+		users: make(map[string]User),
+	}
+
+	if err := s.load(); err != nil {
+		return nil, fmt.Errorf("jsonstream: load %s: %w", path, err)
+	}
+	if err := s.compact(); err != nil {
+		return nil, fmt.Errorf("jsonstream: compact %s: %w", path, err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("jsonstream: open %s: %w", path, err)
+	}
+	s.file = file
+	return s, nil
+}
+
+// load replays the stream file into the in-memory index.
+func (s *JSONStreamStore) load() error {
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_RDONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var op jsonStreamOp
+		if err := json.Unmarshal(line, &op); err != nil {
+			return fmt.Errorf("corrupt line: %w", err)
+		}
+		switch op.Op {
+		case "put":
+			s.index[op.Todo.Id] = op.Todo
+		case "delete":
+			delete(s.index, op.Id)
+		case "user_put":
+			s.users[op.User.Username] = op.User
+		}
+	}
+	return scanner.Err()
+}
+
+// compact rewrites the stream file to hold exactly one "put" per
+// surviving todo, dropping tombstones and superseded writes.
+func (s *JSONStreamStore) compact() error {
+	tmpPath := s.path + ".compact"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	enc := json.NewEncoder(f)
+	for _, todo := range s.index {
+		if err := enc.Encode(jsonStreamOp{Op: "put", Todo: todo}); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	for _, user := range s.users {
+		if err := enc.Encode(jsonStreamOp{Op: "user_put", User: user}); err != nil {
+			f.Close()
+			return err
+		}
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.path)
+}
+
+func (s *JSONStreamStore) append(op jsonStreamOp) error {
+	return json.NewEncoder(s.file).Encode(op)
+}
+
+// Compact reruns compact() against the live store and, if the append
+// handle is already open, reopens it against the rewritten file.
+// NewEventLog calls this once it has replayed history back into the
+// store, since each replayed Create/Update/Delete appends a line here,
+// undoing the compaction NewJSONStreamStore already did on the original
+// file.
+func (s *JSONStreamStore) Compact() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.compact(); err != nil {
+		return err
+	}
+	if s.file == nil {
+		return nil
+	}
+	if err := s.file.Close(); err != nil {
+		return err
+	}
+	file, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	s.file = file
+	return nil
+}
+
+func (s *JSONStreamStore) Create(ctx context.Context, todo Todo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.append(jsonStreamOp{Op: "put", Todo: todo}); err != nil {
+		return err
+	}
+	s.index[todo.Id] = todo
+	return nil
+}
+
+func (s *JSONStreamStore) Get(ctx context.Context, id string) (Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todo, ok := s.index[id]
+	if !ok {
+		return Todo{}, ErrTodoNotFound
+	}
+	return todo, nil
+}
+
+func (s *JSONStreamStore) List(ctx context.Context) ([]Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todos := make([]Todo, 0, len(s.index))
+	for _, todo := range s.index {
+		todos = append(todos, todo)
+	}
+	return todos, nil
+}
+
+func (s *JSONStreamStore) Update(ctx context.Context, todo Todo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.index[todo.Id]; !ok {
+		return ErrTodoNotFound
+	}
+	if err := s.append(jsonStreamOp{Op: "put", Todo: todo}); err != nil {
+		return err
+	}
+	s.index[todo.Id] = todo
+	return nil
+}
+
+func (s *JSONStreamStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.index[id]; !ok {
+		return ErrTodoNotFound
+	}
+	if err := s.append(jsonStreamOp{Op: "delete", Id: id}); err != nil {
+		return err
+	}
+	delete(s.index, id)
+	return nil
+}
+
+func (s *JSONStreamStore) CreateUser(ctx context.Context, user User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[user.Username]; ok {
+		return ErrUsernameTaken
+	}
+	if err := s.append(jsonStreamOp{Op: "user_put", User: user}); err != nil {
+		return err
+	}
+	s.users[user.Username] = user
+	return nil
+}
+
+func (s *JSONStreamStore) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[username]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return user, nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"sync"
+)
+
+// MemoryStore is a process-local TodoStore backed by a map. It is the
+// default driver and loses all data on restart.
+type MemoryStore struct {
+	mu    sync.RWMutex
+	todos map[string]Todo
+	users map[string]User // keyed by username
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		todos: make(map[string]Todo),
+		users: make(map[string]User),
+	}
+}
+
+func (s *MemoryStore) Create(ctx context.Context, todo Todo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.todos[todo.Id] = todo
+	return nil
+}
+
+func (s *MemoryStore) Get(ctx context.Context, id string) (Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todo, ok := s.todos[id]
+	if !ok {
+		return Todo{}, ErrTodoNotFound
+	}
+	return todo, nil
+}
+
+func (s *MemoryStore) List(ctx context.Context) ([]Todo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	todos := make([]Todo, 0, len(s.todos))
+	for _, todo := range s.todos {
+		todos = append(todos, todo)
+	}
+	return todos, nil
+}
+
+func (s *MemoryStore) Update(ctx context.Context, todo Todo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.todos[todo.Id]; !ok {
+		return ErrTodoNotFound
+	}
+	s.todos[todo.Id] = todo
+	return nil
+}
+
+func (s *MemoryStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.todos[id]; !ok {
+		return ErrTodoNotFound
+	}
+	delete(s.todos, id)
+	return nil
+}
+
+func (s *MemoryStore) CreateUser(ctx context.Context, user User) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.users[user.Username]; ok {
+		return ErrUsernameTaken
+	}
+	s.users[user.Username] = user
+	return nil
+}
+
+func (s *MemoryStore) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[username]
+	if !ok {
+		return User{}, ErrUserNotFound
+	}
+	return user, nil
+}
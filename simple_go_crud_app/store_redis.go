@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore persists todos in Redis, one string key per todo holding the
+// JSON-encoded Todo under "todo:<uuid>", and lists with SCAN so it never
+// blocks the server on a large keyspace.
+type RedisStore struct {
+	client *redis.Client
+}
+
+func NewRedisStore(addr string) *RedisStore {
+	return &RedisStore{client: redis.NewClient(&redis.Options{Addr: addr})}
+}
+
+func redisTodoKey(id string) string {
+	return fmt.Sprintf("todo:%s", id)
+}
+
+func redisUserKey(username string) string {
+	return fmt.Sprintf("user:%s", username)
+}
+
+func (s *RedisStore) Create(ctx context.Context, todo Todo) error {
+	data, err := json.Marshal(todo)
+	if err != nil {
+		return err
+	}
+	return s.client.Set(ctx, redisTodoKey(todo.Id), data, 0).Err()
+}
+
+func (s *RedisStore) Get(ctx context.Context, id string) (Todo, error) {
+	data, err := s.client.Get(ctx, redisTodoKey(id)).Bytes()
+	if err == redis.Nil {
+		return Todo{}, ErrTodoNotFound
+	}
+	if err != nil {
+		return Todo{}, err
+	}
+
+	var todo Todo
+	if err := json.Unmarshal(data, &todo); err != nil {
+		return Todo{}, err
+	}
+	return todo, nil
+}
+
+func (s *RedisStore) List(ctx context.Context) ([]Todo, error) {
+	todos := make([]Todo, 0)
+
+	iter := s.client.Scan(ctx, 0, "todo:*", 0).Iterator()
+	for iter.Next(ctx) {
+		data, err := s.client.Get(ctx, iter.Val()).Bytes()
+		if err != nil {
+			continue
+		}
+		var todo Todo
+		if err := json.Unmarshal(data, &todo); err != nil {
+			continue
+		}
+		todos = append(todos, todo)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+	return todos, nil
+}
+
+func (s *RedisStore) Update(ctx context.Context, todo Todo) error {
+	exists, err := s.client.Exists(ctx, redisTodoKey(todo.Id)).Result()
+	if err != nil {
+		return err
+	}
+	if exists == 0 {
+		return ErrTodoNotFound
+	}
+	return s.Create(ctx, todo)
+}
+
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	n, err := s.client.Del(ctx, redisTodoKey(id)).Result()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrTodoNotFound
+	}
+	return nil
+}
+
+func (s *RedisStore) CreateUser(ctx context.Context, user User) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	ok, err := s.client.SetNX(ctx, redisUserKey(user.Username), data, 0).Result()
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return ErrUsernameTaken
+	}
+	return nil
+}
+
+func (s *RedisStore) GetUserByUsername(ctx context.Context, username string) (User, error) {
+	data, err := s.client.Get(ctx, redisUserKey(username)).Bytes()
+	if err == redis.Nil {
+		return User{}, ErrUserNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+
+	var user User
+	if err := json.Unmarshal(data, &user); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Errors
+var (
+	ErrUploadNotFound            = fmt.Errorf("Upload Not Found")
+	ErrUploadExpired             = fmt.Errorf("Upload Expired")
+	ErrUploadContentRangeMissing = fmt.Errorf("Content-Range Header Required")
+	ErrUploadContentRangeInvalid = fmt.Errorf("Content-Range Header Invalid")
+	ErrUploadRangeMismatch       = fmt.Errorf("Content-Range Does Not Match Upload Offset")
+	ErrUploadDigestMissing       = fmt.Errorf("digest Query Param Required")
+	ErrUploadDigestMismatch      = fmt.Errorf("Uploaded Content Does Not Match digest")
+)
+
+const (
+	uploadTmpDir       = "uploads-tmp"
+	uploadExpiry       = 24 * time.Hour
+	uploadDigestPrefix = "sha256:"
+)
+
+// Upload tracks one in-progress resumable upload. mu guards Offset and
+// Hash (and the append to the temp file) so a chunk's body can be
+// streamed without holding the global UploadsDB lock for the duration.
+type Upload struct {
+	Id        string
+	Offset    int64
+	StartedAt time.Time
+	Hash      hash.Hash
+
+	mu sync.Mutex
+}
+
+// UploadsDB is a TodosDB-style in-memory registry of in-progress uploads,
+// guarded by a single mutex.
+type UploadsDB struct {
+	mu      sync.Mutex
+	uploads map[string]*Upload
+}
+
+var uploadsDb UploadsDB
+
+func createUploadTmpDir() {
+	if err := os.MkdirAll(uploadTmpDir, 0755); err != nil {
+		fmt.Println("[-] Error creating upload tmp directory:", err)
+	}
+}
+
+func uploadTmpPath(id string) string {
+	return fmt.Sprintf("%s/%s", uploadTmpDir, id)
+}
+
+// handleUploadStart begins a resumable upload, returning the uuid the
+// client will PATCH chunks to.
+func handleUploadStart(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("[-] Start resumable upload")
+
+	id := uuid.New().String()
+
+	f, err := os.Create(uploadTmpPath(id))
+	if err != nil {
+		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
+		return
+	}
+	f.Close()
+
+	uploadsDb.mu.Lock()
+	uploadsDb.uploads[id] = &Upload{
+		Id:        id,
+		StartedAt: time.Now(),
+		Hash:      sha256.New(),
+	}
+	uploadsDb.mu.Unlock()
+
+	w.Header().Set("Location", fmt.Sprintf("/file/uploads/%s", id))
+	w.Header().Set("Range", "0-0")
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleUploadAppend appends the request body to an in-progress upload at
+// the offset given by the Content-Range header.
+func handleUploadAppend(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	fmt.Println("[-] Append upload chunk", id)
+
+	uploadsDb.mu.Lock()
+	upload, ok := uploadsDb.uploads[id]
+	uploadsDb.mu.Unlock()
+	if !ok {
+		http.Error(w, ErrUploadNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	// Per-upload lock, held for the network read below, so a slow chunk
+	// doesn't block handleUploadStart/handleUploadFinalize for other
+	// uploads which only need the (already released) global lock. It must
+	// never be held while taking uploadsDb.mu (handleUploadFinalize takes
+	// them in that order), so the expired branch below releases it first
+	// instead of nesting uploadsDb.mu inside it.
+	upload.mu.Lock()
+
+	if expired(upload) {
+		upload.mu.Unlock()
+		uploadsDb.mu.Lock()
+		expireUpload(id)
+		uploadsDb.mu.Unlock()
+		http.Error(w, ErrUploadExpired.Error(), http.StatusGone)
+		return
+	}
+	defer upload.mu.Unlock()
+
+	start, err := parseContentRangeStart(r.Header.Get("Content-Range"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if start != upload.Offset {
+		http.Error(w, ErrUploadRangeMismatch.Error(), http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	f, err := os.OpenFile(uploadTmpPath(id), os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
+		return
+	}
+	defer f.Close()
+
+	written, err := io.Copy(io.MultiWriter(f, upload.Hash), r.Body)
+	if err != nil {
+		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	upload.Offset += written
+	w.Header().Set("Range", fmt.Sprintf("0-%d", upload.Offset))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// handleUploadFinalize verifies the digest of the assembled upload and
+// moves it into the files directory.
+func handleUploadFinalize(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	fmt.Println("[-] Finalize upload", id)
+
+	uploadsDb.mu.Lock()
+	defer uploadsDb.mu.Unlock()
+
+	upload, ok := uploadsDb.uploads[id]
+	if !ok {
+		http.Error(w, ErrUploadNotFound.Error(), http.StatusNotFound)
+		return
+	}
+
+	if expired(upload) {
+		expireUpload(id)
+		http.Error(w, ErrUploadExpired.Error(), http.StatusGone)
+		return
+	}
+
+	digest := r.URL.Query().Get("digest")
+	if digest == "" {
+		http.Error(w, ErrUploadDigestMissing.Error(), http.StatusBadRequest)
+		return
+	}
+
+	upload.mu.Lock()
+	gotSum := fmt.Sprintf("%x", upload.Hash.Sum(nil))
+	upload.mu.Unlock()
+
+	wantSum := strings.TrimPrefix(digest, uploadDigestPrefix)
+	if wantSum != gotSum {
+		http.Error(w, ErrUploadDigestMismatch.Error(), http.StatusBadRequest)
+		return
+	}
+
+	finalPath := fmt.Sprintf("%s/%s", fileUploadFolder, id)
+	if err := os.Rename(uploadTmpPath(id), finalPath); err != nil {
+		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	delete(uploadsDb.uploads, id)
+	w.WriteHeader(http.StatusCreated)
+}
+
+func expired(upload *Upload) bool {
+	return time.Since(upload.StartedAt) > uploadExpiry
+}
+
+// expireUpload removes a stale upload's state and temp file. Callers must
+// hold uploadsDb.mu.
+func expireUpload(id string) {
+	os.Remove(uploadTmpPath(id))
+	delete(uploadsDb.uploads, id)
+}
+
+// parseContentRangeStart reads the start offset out of a "bytes start-end"
+// Content-Range header.
+func parseContentRangeStart(header string) (int64, error) {
+	if header == "" {
+		return 0, ErrUploadContentRangeMissing
+	}
+
+	header = strings.TrimPrefix(header, "bytes ")
+	parts := strings.SplitN(header, "-", 2)
+	if len(parts) != 2 {
+		return 0, ErrUploadContentRangeInvalid
+	}
+
+	start, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return 0, ErrUploadContentRangeInvalid
+	}
+	return start, nil
+}
@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Errors
+var (
+	ErrUserNotFound       = errors.New("User Not Found")
+	ErrUsernameTaken      = errors.New("Username Already Taken")
+	ErrInvalidCredentials = errors.New("Invalid Username Or Password")
+	ErrUnauthorized       = errors.New("Unauthorized")
+)
+
+// ContextKey namespaces context values so auth data doesn't collide with
+// other context keys.
+type ContextKey string
+
+const userIDContextKey ContextKey = "userID"
+
+type User struct {
+	Id           string `json:"id"`
+	Username     string `json:"username"`
+	PasswordHash string `json:"-"`
+}
+
+type RegisterRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type LoginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+type LoginResponse struct {
+	Token string `json:"token"`
+}
+
+var userStore UserStore
+
+// authCookieName holds the JWT for browsers, which can't attach an
+// Authorization header to normal navigations or htmx form submissions.
+const authCookieName = "auth_token"
+
+func jwtSecret() []byte {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = "dev-secret-change-me"
+	}
+	return []byte(secret)
+}
+
+const tokenTTL = 24 * time.Hour
+
+func issueToken(userID string) (string, error) {
+	claims := jwt.MapClaims{
+		"sub": userID,
+		"exp": time.Now().Add(tokenTTL).Unix(),
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString(jwtSecret())
+}
+
+func parseToken(tokenString string) (string, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		return jwtSecret(), nil
+	})
+	if err != nil || !token.Valid {
+		return "", ErrUnauthorized
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", ErrUnauthorized
+	}
+
+	userID, ok := claims["sub"].(string)
+	if !ok {
+		return "", ErrUnauthorized
+	}
+	return userID, nil
+}
+
+func userIDFromContext(ctx context.Context) string {
+	userID, _ := ctx.Value(userIDContextKey).(string)
+	return userID
+}
+
+// bearerToken extracts the token from an API caller's "Authorization:
+// Bearer <token>" header, and tokenFromRequest falls back to the
+// auth_token cookie the browser carries once handleLogin sets it, so the
+// server-rendered UI can authenticate without a way to send that header.
+func bearerToken(r *http.Request) (string, bool) {
+	authHeader := r.Header.Get("Authorization")
+	if !strings.HasPrefix(authHeader, "Bearer ") {
+		return "", false
+	}
+	return strings.TrimPrefix(authHeader, "Bearer "), true
+}
+
+func tokenFromRequest(r *http.Request) (string, bool) {
+	if token, ok := bearerToken(r); ok {
+		return token, true
+	}
+	if cookie, err := r.Cookie(authCookieName); err == nil {
+		return cookie.Value, true
+	}
+	return "", false
+}
+
+// authMiddleware requires a valid token, either an "Authorization: Bearer
+// <token>" header for API callers or an auth_token cookie for the browser,
+// and injects the authenticated user id into the request context.
+func authMiddleware(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := tokenFromRequest(r)
+		if !ok {
+			unauthorized(w, r)
+			return
+		}
+
+		userID, err := parseToken(token)
+		if err != nil {
+			unauthorized(w, r)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), userIDContextKey, userID)
+		next(w, r.WithContext(ctx))
+	}
+}
+
+// unauthorized responds 401 to API callers, and sends browsers to the
+// login page instead, since a bare 401 leaves the server-rendered UI with
+// no way to reach a form that can get it a session cookie.
+func unauthorized(w http.ResponseWriter, r *http.Request) {
+	if wantsJSON(r) {
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func handleRegister(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("[-] Register User")
+
+	var req RegisterRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, ErrJsonInvalid.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		// The register.html form submits as regular form data.
+		req.Username = r.FormValue("username")
+		req.Password = r.FormValue("password")
+	}
+	if req.Username == "" || req.Password == "" {
+		http.Error(w, ErrTodoEmpty.Error(), http.StatusBadRequest)
+		return
+	}
+
+	passwordHash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	user := User{
+		Id:           uuid.New().String(),
+		Username:     req.Username,
+		PasswordHash: string(passwordHash),
+	}
+	if err := userStore.CreateUser(r.Context(), user); err != nil {
+		if errors.Is(err, ErrUsernameTaken) {
+			http.Error(w, ErrUsernameTaken.Error(), http.StatusConflict)
+			return
+		}
+		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if wantsJSON(r) {
+		w.WriteHeader(http.StatusCreated)
+		return
+	}
+	http.Redirect(w, r, "/login", http.StatusSeeOther)
+}
+
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	fmt.Println("[-] Login User")
+
+	var req LoginRequest
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, ErrJsonInvalid.Error(), http.StatusBadRequest)
+			return
+		}
+	} else {
+		// The login.html form submits as regular form data.
+		req.Username = r.FormValue("username")
+		req.Password = r.FormValue("password")
+	}
+
+	user, err := userStore.GetUserByUsername(r.Context(), req.Username)
+	if err != nil {
+		http.Error(w, ErrInvalidCredentials.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)); err != nil {
+		http.Error(w, ErrInvalidCredentials.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	token, err := issueToken(user.Id)
+	if err != nil {
+		http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Also set the token as a cookie so the server-rendered UI, which has
+	// no way to attach an Authorization header, stays logged in.
+	http.SetCookie(w, &http.Cookie{
+		Name:     authCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(tokenTTL.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	if wantsJSON(r) {
+		jsonResponse, err := json.Marshal(LoginResponse{Token: token})
+		if err != nil {
+			http.Error(w, ErrInternalServerError.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(jsonResponse)
+		return
+	}
+
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
@@ -10,33 +10,43 @@ import (
 	"regexp"
 	"strings"
 	// "time"
+
+	"github.com/golang-jwt/jwt/v5"
 )
 
 type route struct {
 	method       string
 	pattern      *regexp.Regexp
+	rawPattern   string
 	innerHandler http.HandlerFunc
 	paramsKeys   []string
 }
 
 type router struct {
-	routes []route
+	routes      []route
+	middlewares []func(http.Handler) http.Handler
 }
 
-// A wrapper for logging
-func (r *route) handler(w http.ResponseWriter, req *http.Request) {
-	fmt.Sprintln(req.Method, " ", req.URL)
-	r.innerHandler(w, req)
+// Use registers middleware to run around every matched route, in the
+// order given: the first middleware passed is the outermost.
+func (r *router) Use(mw ...func(http.Handler) http.Handler) {
+	r.middlewares = append(r.middlewares, mw...)
+}
 
-	requestString := fmt.Sprint(req.Method, " ", req.URL)
-	fmt.Println("received ", requestString)
-	// start := time.Now()
-	r.innerHandler(NewResponseWriter(w), req)
-	// w.Time = time.Since(start).Milliseconds()
-	fmt.Printf("%s resolved with %s\n", requestString, w)
+// wrap composes the router's middleware chain around a route's handler.
+// It runs once per request, unlike the route.handler wrapper it replaced,
+// which invoked innerHandler twice.
+func (r *router) wrap(rt route) http.Handler {
+	var handler http.Handler = rt.innerHandler
+	for i := len(r.middlewares) - 1; i >= 0; i-- {
+		handler = r.middlewares[i](handler)
+	}
+	return handler
 }
 
 func (r *router) addRoute(method, endpoint string, handler http.HandlerFunc) {
+	rawPattern := endpoint
+
 	// path params
 	pathParamPattern := regexp.MustCompile(":([a-z]+)")
 	matches := pathParamPattern.FindAllStringSubmatch(endpoint, -1)
@@ -51,7 +61,7 @@ func (r *router) addRoute(method, endpoint string, handler http.HandlerFunc) {
 		}
 	}
 
-	route := route{method, regexp.MustCompile("^" + endpoint + "$"), handler, paramKeys}
+	route := route{method, regexp.MustCompile("^" + endpoint + "$"), rawPattern, handler, paramKeys}
 	r.routes = append(r.routes, route)
 }
 
@@ -69,10 +79,11 @@ func (r *router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 				allow = append(allow, route.method)
 				continue
 			}
-			route.handler(
-				w,
-				buildContext(req, route.paramsKeys, matches[1:]),
-			)
+
+			matchedReq := buildContext(req, route.paramsKeys, matches[1:])
+			matchedReq = matchedReq.WithContext(context.WithValue(matchedReq.Context(), routePatternContextKey, route.rawPattern))
+
+			r.wrap(route).ServeHTTP(NewResponseWriter(w), matchedReq)
 			return
 		}
 	}
@@ -88,6 +99,61 @@ func (r *router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 // it serves as a domain for the context keys
 type ContextKey string
 
+const authUserContextKey ContextKey = "userID"
+const requestIDContextKey ContextKey = "requestID"
+const routePatternContextKey ContextKey = "routePattern"
+
+func authSecret() []byte {
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		secret = "dev-secret-change-me"
+	}
+	return []byte(secret)
+}
+
+// parseAuthToken validates a JWT issued by the crud app's auth service
+// and returns the subject (user id) it was issued for.
+func parseAuthToken(tokenString string) (string, error) {
+	token, err := jwt.Parse(tokenString, func(t *jwt.Token) (interface{}, error) {
+		return authSecret(), nil
+	})
+	if err != nil || !token.Valid {
+		return "", fmt.Errorf("invalid token")
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return "", fmt.Errorf("invalid claims")
+	}
+
+	userID, ok := claims["sub"].(string)
+	if !ok {
+		return "", fmt.Errorf("invalid subject")
+	}
+	return userID, nil
+}
+
+// requireAuth wraps a handler with a "Authorization: Bearer <token>"
+// check, injecting the authenticated user id into the request context.
+func requireAuth(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authHeader := r.Header.Get("Authorization")
+		if !strings.HasPrefix(authHeader, "Bearer ") {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		userID, err := parseAuthToken(strings.TrimPrefix(authHeader, "Bearer "))
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), authUserContextKey, userID)
+		next(w, r.WithContext(ctx))
+	}
+}
+
 // Returns a shallow-copy of the request with an updated context,
 // including path parameters
 func buildContext(req *http.Request, paramKeys, paramValues []string) *http.Request {
@@ -101,6 +167,7 @@ func buildContext(req *http.Request, paramKeys, paramValues []string) *http.Requ
 type ResponseWriter struct {
 	Status int
 	Body   string
+	Bytes  int64
 	Time   int64
 	http.ResponseWriter
 }
@@ -115,9 +182,14 @@ func (w *ResponseWriter) WriteHeader(code int) {
 	w.ResponseWriter.WriteHeader(code)
 }
 
+// Write may be called more than once per response (e.g. metricsRegistry's
+// ServeHTTP does several Fprintf calls), so Bytes accumulates across calls
+// while Body keeps only the most recent chunk for String()'s debug preview.
 func (w *ResponseWriter) Write(body []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(body)
 	w.Body = string(body)
-	return w.ResponseWriter.Write(body)
+	w.Bytes += int64(n)
+	return n, err
 }
 
 // Overwrite the string method to see what the log looks like
@@ -150,13 +222,17 @@ func newRouter() *router {
 
 func main() {
 	router := newRouter()
-	router.GET("/ping/:id/:otherid", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+	metrics := newMetricsRegistry()
+	router.Use(requestIDMiddleware, loggingMiddleware, metricsMiddleware(metrics), recoveryMiddleware)
+
+	router.GET("/ping/:id/:otherid", requireAuth(func(w http.ResponseWriter, r *http.Request) {
 		fmt.Println(r.Context().Value(ContextKey("id")))
 		fmt.Println(r.Context().Value(ContextKey("otherid")))
 		fmt.Println(r.FormValue("name"))
-		
-
+		fmt.Println("authenticated as", r.Context().Value(authUserContextKey))
 	}))
+	router.GET("/metrics", metrics.ServeHTTP)
 
 	l, err := net.Listen("tcp", ":8081")
 	if err != nil {
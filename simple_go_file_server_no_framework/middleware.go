@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var requestSeq uint64
+
+// requestIDMiddleware stamps every request with a unique id, available to
+// downstream handlers and middleware via requestIDContextKey.
+func requestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := atomic.AddUint64(&requestSeq, 1)
+		ctx := context.WithValue(r.Context(), requestIDContextKey, fmt.Sprintf("req-%d", id))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// recoveryMiddleware turns a panicking handler into a 500 instead of
+// crashing the server, folding in the ad-hoc recover() pattern the crud
+// app's createTodo handler used.
+func recoveryMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if rec := recover(); rec != nil {
+				fmt.Println("[-] Panic recovered:", rec)
+				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			}
+		}()
+		next.ServeHTTP(w, r)
+	})
+}
+
+// metricsMiddleware times the request and records it against the route
+// pattern stashed in the context by router.ServeHTTP, and populates
+// ResponseWriter.Time so loggingMiddleware can report the same latency.
+func metricsMiddleware(reg *metricsRegistry) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			next.ServeHTTP(w, r)
+			latencyMs := time.Since(start).Milliseconds()
+
+			if rw, ok := w.(*ResponseWriter); ok {
+				rw.Time = latencyMs
+			}
+
+			pattern, _ := r.Context().Value(routePatternContextKey).(string)
+			reg.observe(fmt.Sprintf("%s %s", r.Method, pattern), latencyMs)
+		})
+	}
+}
+
+// loggingMiddleware writes one structured JSON line per request. It must
+// run after metricsMiddleware in the chain so ResponseWriter.Time is
+// already populated.
+func loggingMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		rw, ok := w.(*ResponseWriter)
+		if !ok {
+			return
+		}
+
+		entry, err := json.Marshal(map[string]any{
+			"method":     r.Method,
+			"path":       r.URL.Path,
+			"status":     rw.Status,
+			"bytes":      rw.Bytes,
+			"latency_ms": rw.Time,
+			"request_id": r.Context().Value(requestIDContextKey),
+		})
+		if err != nil {
+			fmt.Println("[-] Error marshaling log entry:", err)
+			return
+		}
+		fmt.Println(string(entry))
+	})
+}
+
+// latencyBucketBoundsMs are the fixed "le" bucket boundaries, in
+// milliseconds, for the http_request_latency_ms histogram. Each routeStats
+// keeps one cumulative counter per bound, plus the mandatory +Inf bucket
+// (routeStats.count).
+var latencyBucketBoundsMs = []int64{5, 10, 25, 50, 100, 250, 500, 1000, 2500, 5000}
+
+// routeStats accumulates a Prometheus-style counter, latency total and
+// latency histogram buckets for one "METHOD pattern" route key. buckets[i]
+// counts requests with latency <= latencyBucketBoundsMs[i], so it's already
+// cumulative in bound order.
+type routeStats struct {
+	count      uint64
+	latencySum int64
+	buckets    []uint64
+}
+
+func newRouteStats() *routeStats {
+	return &routeStats{buckets: make([]uint64, len(latencyBucketBoundsMs))}
+}
+
+// metricsRegistry tracks per-route request counts and latency, and
+// exposes them at /metrics in Prometheus text exposition format.
+type metricsRegistry struct {
+	mu    sync.Mutex
+	stats map[string]*routeStats
+}
+
+func newMetricsRegistry() *metricsRegistry {
+	return &metricsRegistry{stats: make(map[string]*routeStats)}
+}
+
+func (m *metricsRegistry) observe(routeKey string, latencyMs int64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.stats[routeKey]
+	if !ok {
+		s = newRouteStats()
+		m.stats[routeKey] = s
+	}
+	s.count++
+	s.latencySum += latencyMs
+	for i, bound := range latencyBucketBoundsMs {
+		if latencyMs <= bound {
+			s.buckets[i]++
+		}
+	}
+}
+
+func (m *metricsRegistry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+
+	fmt.Fprintln(w, "# HELP http_requests_total Total number of requests per route.")
+	fmt.Fprintln(w, "# TYPE http_requests_total counter")
+	for routeKey, s := range m.stats {
+		fmt.Fprintf(w, "http_requests_total{route=%q} %d\n", routeKey, s.count)
+	}
+
+	fmt.Fprintln(w, "# HELP http_request_latency_ms Request latency in milliseconds per route.")
+	fmt.Fprintln(w, "# TYPE http_request_latency_ms histogram")
+	for routeKey, s := range m.stats {
+		for i, bound := range latencyBucketBoundsMs {
+			fmt.Fprintf(w, "http_request_latency_ms_bucket{route=%q,le=\"%d\"} %d\n", routeKey, bound, s.buckets[i])
+		}
+		fmt.Fprintf(w, "http_request_latency_ms_bucket{route=%q,le=\"+Inf\"} %d\n", routeKey, s.count)
+		fmt.Fprintf(w, "http_request_latency_ms_sum{route=%q} %d\n", routeKey, s.latencySum)
+		fmt.Fprintf(w, "http_request_latency_ms_count{route=%q} %d\n", routeKey, s.count)
+	}
+}